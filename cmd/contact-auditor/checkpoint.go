@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// loadCheckpoint reads the last successfully processed registration ID from
+// the auditor's checkpoint file, so an interrupted run can resume instead of
+// rescanning from the beginning. Returns 0, the starting point for a fresh
+// run, if checkpointing is disabled or no checkpoint file exists yet.
+func (c contactAuditor) loadCheckpoint() (int64, error) {
+	if c.checkpointPath == "" {
+		return 0, nil
+	}
+
+	data, err := os.ReadFile(c.checkpointPath)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("reading checkpoint file %q: %w", c.checkpointPath, err)
+	}
+
+	id, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing checkpoint file %q: %w", c.checkpointPath, err)
+	}
+	return id, nil
+}
+
+// saveCheckpoint durably records lastID as the last successfully processed
+// registration ID. It writes to a temporary file and renames it into place
+// so that a crash mid-write can't leave a corrupt checkpoint behind.
+func (c contactAuditor) saveCheckpoint(lastID int64) error {
+	if c.checkpointPath == "" {
+		return nil
+	}
+
+	tmpPath := c.checkpointPath + ".tmp"
+	err := os.WriteFile(tmpPath, []byte(strconv.FormatInt(lastID, 10)), 0644)
+	if err != nil {
+		return fmt.Errorf("writing checkpoint file %q: %w", tmpPath, err)
+	}
+	return os.Rename(tmpPath, c.checkpointPath)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	c := contactAuditor{checkpointPath: filepath.Join(t.TempDir(), "checkpoint")}
+
+	// No checkpoint file yet: a fresh run starts from 0.
+	afterID, err := c.loadCheckpoint()
+	test.AssertNotError(t, err, "loading a nonexistent checkpoint")
+	test.AssertEquals(t, afterID, int64(0))
+
+	err = c.saveCheckpoint(12345)
+	test.AssertNotError(t, err, "saving a checkpoint")
+
+	afterID, err = c.loadCheckpoint()
+	test.AssertNotError(t, err, "loading a saved checkpoint")
+	test.AssertEquals(t, afterID, int64(12345))
+
+	// A later checkpoint overwrites the earlier one.
+	err = c.saveCheckpoint(99999)
+	test.AssertNotError(t, err, "overwriting a checkpoint")
+
+	afterID, err = c.loadCheckpoint()
+	test.AssertNotError(t, err, "loading an overwritten checkpoint")
+	test.AssertEquals(t, afterID, int64(99999))
+}
+
+func TestCheckpointDisabled(t *testing.T) {
+	c := contactAuditor{checkpointPath: ""}
+
+	afterID, err := c.loadCheckpoint()
+	test.AssertNotError(t, err, "loading with checkpointing disabled")
+	test.AssertEquals(t, afterID, int64(0))
+
+	err = c.saveCheckpoint(42)
+	test.AssertNotError(t, err, "saving with checkpointing disabled")
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// schemaVersion is bumped whenever the shape of a finding changes in a way
+// that downstream consumers need to know about.
+const schemaVersion = 1
+
+// severity classifies how serious a finding is. Today every built-in check
+// only ever produces errors, but the field exists so future soft checks
+// (e.g. deprecation warnings) don't require a schema bump.
+type severity string
+
+const (
+	severityError severity = "error"
+)
+
+// problemCode is a stable, machine-readable identifier for a class of
+// contact problem. Downstream tooling (alerting, dashboards) should key off
+// of this rather than parsing problemMessage.
+type problemCode string
+
+const (
+	problemMissingMailtoPrefix problemCode = "missing_mailto_prefix"
+	problemInvalidEmailSyntax  problemCode = "invalid_email_syntax"
+	problemUnmarshalError      problemCode = "unmarshal_error"
+	problemAddressTooLong      problemCode = "address_exceeds_rfc5321_limits"
+	problemIDNNormalization    problemCode = "idn_normalization_failed"
+	problemNoMXRecord          problemCode = "no_mx_record"
+	problemMXLookupFailed      problemCode = "mx_lookup_failed"
+	problemSMTPRCPTRejected    problemCode = "smtp_rcpt_rejected"
+	problemDisposableDomain    problemCode = "disposable_domain"
+)
+
+// finding represents a single problem found with a single contact entry (or,
+// in the case of problemUnmarshalError, with the contact column as a whole).
+type finding struct {
+	SchemaVersion  int         `json:"schema_version"`
+	RegistrationID int64       `json:"registration_id"`
+	CreatedAt      string      `json:"created_at"`
+	Contact        string      `json:"contact,omitempty"`
+	ProblemCode    problemCode `json:"problem_code"`
+	ProblemMessage string      `json:"problem_message"`
+	Severity       severity    `json:"severity"`
+}
+
+// resultEmitter renders a finding as a line of output in some format. A
+// single emitter is used for the lifetime of a run, selected by --format.
+type resultEmitter interface {
+	emit(f finding) (string, error)
+}
+
+// ndjsonEmitter renders each finding as a single line of JSON, newline
+// delimited, suitable for streaming into log aggregation or jq.
+type ndjsonEmitter struct{}
+
+func (ndjsonEmitter) emit(f finding) (string, error) {
+	f.SchemaVersion = schemaVersion
+	line, err := json.Marshal(f)
+	if err != nil {
+		return "", err
+	}
+	return string(line) + "\n", nil
+}
+
+// tsvEmitter renders each finding as a tab-separated line, matching the
+// auditor's original output format. Kept as the default for backwards
+// compatibility with existing scripts that grep or awk over the results.
+type tsvEmitter struct{}
+
+func (tsvEmitter) emit(f finding) (string, error) {
+	return fmt.Sprintf("%d\t%s\t%s\t%q\t%q\n", f.RegistrationID, f.CreatedAt, f.ProblemCode, f.Contact, f.ProblemMessage), nil
+}
+
+// newResultEmitter returns the resultEmitter named by format, or an error if
+// format doesn't name a known emitter.
+func newResultEmitter(format string) (resultEmitter, error) {
+	switch format {
+	case "tsv":
+		return tsvEmitter{}, nil
+	case "ndjson":
+		return ndjsonEmitter{}, nil
+	default:
+		return nil, fmt.Errorf("unrecognized --format %q, must be one of: tsv, ndjson", format)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestNDJSONEmitterRoundTrips(t *testing.T) {
+	f := finding{
+		RegistrationID: 1,
+		CreatedAt:      "2026-01-01 00:00:00",
+		Contact:        "mailto:bad@example.com",
+		ProblemCode:    problemInvalidEmailSyntax,
+		ProblemMessage: "bad syntax",
+		Severity:       severityError,
+	}
+
+	line, err := ndjsonEmitter{}.emit(f)
+	test.AssertNotError(t, err, "emitting an NDJSON line")
+
+	if line[len(line)-1] != '\n' {
+		t.Errorf("expected the emitted line to end in a newline, got: %q", line)
+	}
+
+	var got finding
+	err = json.Unmarshal([]byte(line), &got)
+	test.AssertNotError(t, err, "unmarshaling the emitted NDJSON line")
+
+	test.AssertEquals(t, got.SchemaVersion, schemaVersion)
+	test.AssertEquals(t, got.RegistrationID, f.RegistrationID)
+	test.AssertEquals(t, got.CreatedAt, f.CreatedAt)
+	test.AssertEquals(t, got.Contact, f.Contact)
+	test.AssertEquals(t, got.ProblemCode, f.ProblemCode)
+	test.AssertEquals(t, got.ProblemMessage, f.ProblemMessage)
+	test.AssertEquals(t, got.Severity, f.Severity)
+}
+
+func TestTSVEmitterMatchesLegacyLayout(t *testing.T) {
+	f := finding{
+		RegistrationID: 42,
+		CreatedAt:      "2026-01-01 00:00:00",
+		Contact:        "mailto:bad@example.com",
+		ProblemCode:    problemInvalidEmailSyntax,
+		ProblemMessage: "bad syntax",
+	}
+
+	line, err := tsvEmitter{}.emit(f)
+	test.AssertNotError(t, err, "emitting a TSV line")
+
+	want := "42\t2026-01-01 00:00:00\tinvalid_email_syntax\t\"mailto:bad@example.com\"\t\"bad syntax\"\n"
+	test.AssertEquals(t, line, want)
+}
+
+func TestNewResultEmitter(t *testing.T) {
+	if _, err := newResultEmitter("tsv"); err != nil {
+		t.Errorf("expected \"tsv\" to be a recognized format, got: %s", err)
+	}
+	if _, err := newResultEmitter("ndjson"); err != nil {
+		t.Errorf("expected \"ndjson\" to be a recognized format, got: %s", err)
+	}
+	if _, err := newResultEmitter("xml"); err == nil {
+		t.Errorf("expected an error for an unrecognized format, got none")
+	}
+}
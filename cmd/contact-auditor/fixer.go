@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"google.golang.org/grpc"
+
+	"github.com/letsencrypt/boulder/cmd"
+	corepb "github.com/letsencrypt/boulder/core/proto"
+	bgrpc "github.com/letsencrypt/boulder/grpc"
+	blog "github.com/letsencrypt/boulder/log"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+)
+
+// registrationMutator is the subset of the SA gRPC client the fixer needs
+// to strip contacts from a registration. It's scoped down from the full
+// sapb.StorageAuthorityClient so that tests can supply a fake without
+// having to implement every SA RPC.
+type registrationMutator interface {
+	GetRegistration(ctx context.Context, req *sapb.RegistrationID, opts ...grpc.CallOption) (*corepb.Registration, error)
+	UpdateRegistration(ctx context.Context, req *corepb.Registration, opts ...grpc.CallOption) (*corepb.Registration, error)
+}
+
+// fixMode selects what, if anything, the fixer does with a registration
+// once it's found to have invalid contacts.
+type fixMode string
+
+const (
+	// fixDryRun computes and emits the before/after diff a real fix would
+	// make, without mutating anything. Leaving --fix unset disables the
+	// fixer entirely, so no diffs are computed or emitted in that case.
+	fixDryRun fixMode = "dry-run"
+	// fixQuarantine tags the registration in contact_audit_quarantine for
+	// follow-up, without touching its contact array.
+	fixQuarantine fixMode = "quarantine"
+	// fixStrip removes the offending entries from the registration's
+	// contact array via the SA, nulling it out entirely if every entry was
+	// invalid.
+	fixStrip fixMode = "strip"
+)
+
+// parseFixMode validates that s names one of the known fix modes.
+func parseFixMode(s string) (fixMode, error) {
+	switch fixMode(s) {
+	case fixDryRun, fixQuarantine, fixStrip:
+		return fixMode(s), nil
+	default:
+		return "", fmt.Errorf("unrecognized --fix mode %q, must be one of: %s, %s, %s", s, fixDryRun, fixQuarantine, fixStrip)
+	}
+}
+
+// contactDiff is an auditable, before/after record of a mutation the fixer
+// made (or, in dry-run mode, would have made) to a registration's contacts.
+// Unlike findings, diffs are always emitted as JSON regardless of
+// --format, since they're inherently structured records meant to be
+// archived rather than grepped.
+type contactDiff struct {
+	SchemaVersion  int      `json:"schema_version"`
+	RegistrationID int64    `json:"registration_id"`
+	CreatedAt      string   `json:"created_at"`
+	Mode           fixMode  `json:"mode"`
+	Before         []string `json:"before"`
+	After          []string `json:"after"`
+}
+
+// fixer applies --fix mutations to registrations with invalid contacts.
+type fixer struct {
+	sac    registrationMutator
+	db     *sql.DB
+	mode   fixMode
+	logger blog.Logger
+}
+
+// newFixer constructs a fixer for the given mode. sac may be nil when mode
+// is fixQuarantine or fixDryRun, since neither calls into the SA.
+func newFixer(mode fixMode, sac registrationMutator, db *sql.DB, logger blog.Logger) *fixer {
+	return &fixer{sac: sac, db: db, mode: mode, logger: logger}
+}
+
+// confirmationToken computes the HMAC-SHA256 of mode, keyed by secret, as a
+// hex string. It's scoped to a single mode so that a token minted for
+// --fix=quarantine can't be replayed against --fix=strip.
+func confirmationToken(secret string, mode fixMode) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(mode))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// checkConfirmationToken fails closed: it returns an error unless got is
+// the HMAC-SHA256 of mode keyed by secret, so that operator tooling can't
+// be tricked into a mass mutation via a guessed or copy-pasted value. The
+// secret lives only in the config file; the derived, mode-scoped token is
+// what's passed on the command line via --confirm-token, generated ahead
+// of time by whoever holds the secret. It's a no-op in dry-run mode, which
+// never mutates anything.
+func checkConfirmationToken(mode fixMode, secret, got string) error {
+	if mode == fixDryRun {
+		return nil
+	}
+	if secret == "" {
+		return fmt.Errorf("--fix=%s requires contactAuditor.fix.confirmationSecret to be configured", mode)
+	}
+	want := confirmationToken(secret, mode)
+	if subtle.ConstantTimeCompare([]byte(want), []byte(got)) != 1 {
+		return fmt.Errorf("--confirm-token does not match the HMAC-signed token for --fix=%s", mode)
+	}
+	return nil
+}
+
+// invalidContacts returns the subset of contacts that findings flagged as
+// invalid, in their original order.
+func invalidContacts(contacts []string, findings []finding) []string {
+	invalid := make(map[string]struct{}, len(findings))
+	for _, f := range findings {
+		invalid[f.Contact] = struct{}{}
+	}
+
+	var result []string
+	for _, contact := range contacts {
+		if _, ok := invalid[contact]; ok {
+			result = append(result, contact)
+		}
+	}
+	return result
+}
+
+// buildDiff computes the before/after diff of removing every contact that
+// findings flag as invalid from contacts. It returns a nil diff if none of
+// the findings point at a specific contact to remove (e.g. an unmarshal
+// error).
+func buildDiff(id int64, createdAt string, mode fixMode, contacts []string, findings []finding) *contactDiff {
+	toRemove := invalidContacts(contacts, findings)
+	if len(toRemove) == 0 {
+		return nil
+	}
+
+	removeSet := make(map[string]struct{}, len(toRemove))
+	for _, contact := range toRemove {
+		removeSet[contact] = struct{}{}
+	}
+
+	var after []string
+	for _, contact := range contacts {
+		if _, removed := removeSet[contact]; !removed {
+			after = append(after, contact)
+		}
+	}
+
+	return &contactDiff{
+		SchemaVersion:  schemaVersion,
+		RegistrationID: id,
+		CreatedAt:      createdAt,
+		Mode:           mode,
+		Before:         contacts,
+		After:          after,
+	}
+}
+
+// apply fixes up a single registration's contacts according to f.mode and
+// returns the before/after diff that was (or, in dry-run mode, would have
+// been) applied. A nil diff is returned if none of the findings point at a
+// specific contact to remove (e.g. an unmarshal error).
+func (f *fixer) apply(ctx context.Context, id int64, createdAt string, contacts []string, findings []finding) (*contactDiff, error) {
+	diff := buildDiff(id, createdAt, f.mode, contacts, findings)
+	if diff == nil {
+		return nil, nil
+	}
+
+	switch f.mode {
+	case fixDryRun:
+		// Nothing to do, just report what would have happened.
+
+	case fixQuarantine:
+		_, err := f.db.ExecContext(ctx,
+			`INSERT INTO contact_audit_quarantine (registrationID, createdAt, invalidContacts)
+			VALUES (?, ?, ?)`,
+			id, createdAt, mustMarshal(invalidContacts(contacts, findings)))
+		if err != nil {
+			return nil, fmt.Errorf("quarantining registration %d: %w", id, err)
+		}
+
+	case fixStrip:
+		// The SA only exposes a generic UpdateRegistration RPC that takes the
+		// whole registration, so fetch it, mutate the Contact field, and
+		// write the whole thing back, the same way other Boulder admin
+		// tools update a registration. Recompute the diff against the
+		// freshly fetched contacts rather than trusting the audit's
+		// potentially stale snapshot: in a long-running, checkpointed scan
+		// the registration may have been edited (by the subscriber, or by
+		// an earlier --fix pass) since it was read, and both the mutation
+		// and the diff we emit need to reflect what's actually there now.
+		reg, err := f.sac.GetRegistration(ctx, &sapb.RegistrationID{Id: id})
+		if err != nil {
+			return nil, fmt.Errorf("fetching registration %d: %w", id, err)
+		}
+		if !slices.Equal(reg.Contact, contacts) {
+			diff = buildDiff(id, createdAt, f.mode, reg.Contact, findings)
+			if diff == nil {
+				return nil, nil
+			}
+		}
+		reg.Contact = diff.After
+		_, err = f.sac.UpdateRegistration(ctx, reg)
+		if err != nil {
+			return nil, fmt.Errorf("stripping contacts for registration %d: %w", id, err)
+		}
+	}
+
+	return diff, nil
+}
+
+// mustMarshal marshals v to JSON, falling back to a best-effort string
+// representation on failure. Its callers (a []string of invalid contacts
+// here, and a *contactDiff in main.go) always pass values built entirely out
+// of strings and integers, so the fallback path should be unreachable in
+// practice.
+func mustMarshal(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+// setupSAClient dials the SA gRPC service named in cfg, for use by the
+// strip fix mode.
+func setupSAClient(cfg cmd.GRPCClientConfig, tlsConfig cmd.TLSConfig) (registrationMutator, error) {
+	tlsCreds, err := tlsConfig.Load(nil)
+	if err != nil {
+		return nil, fmt.Errorf("loading TLS config: %w", err)
+	}
+
+	conn, err := bgrpc.ClientSetup(&cfg, tlsCreds, nil, cmd.Clock())
+	if err != nil {
+		return nil, fmt.Errorf("dialing SA: %w", err)
+	}
+
+	return sapb.NewStorageAuthorityClient(conn), nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"google.golang.org/grpc"
+
+	corepb "github.com/letsencrypt/boulder/core/proto"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+	"github.com/letsencrypt/boulder/test"
+)
+
+var testFindings = []finding{
+	{
+		RegistrationID: 1,
+		Contact:        "mailto:bad@example.com",
+		ProblemCode:    problemInvalidEmailSyntax,
+		ProblemMessage: "bad syntax",
+	},
+}
+
+func TestFixerApplyDryRun(t *testing.T) {
+	f := newFixer(fixDryRun, nil, nil, nil)
+
+	diff, err := f.apply(context.Background(), 1, "2026-01-01 00:00:00", []string{"mailto:good@example.com", "mailto:bad@example.com"}, testFindings)
+	test.AssertNotError(t, err, "applying a dry-run fix")
+	test.AssertNotNil(t, diff, "diff")
+	test.AssertDeepEquals(t, diff.Before, []string{"mailto:good@example.com", "mailto:bad@example.com"})
+	test.AssertDeepEquals(t, diff.After, []string{"mailto:good@example.com"})
+}
+
+func TestFixerApplyNoInvalidContacts(t *testing.T) {
+	f := newFixer(fixDryRun, nil, nil, nil)
+
+	diff, err := f.apply(context.Background(), 1, "2026-01-01 00:00:00", []string{"mailto:good@example.com"}, nil)
+	test.AssertNotError(t, err, "applying a fix with no findings")
+	if diff != nil {
+		t.Errorf("expected a nil diff when no findings point at a contact to remove, got: %+v", diff)
+	}
+}
+
+type fakeRegistrationMutator struct {
+	reg             *corepb.Registration
+	updatedContacts []string
+}
+
+func (f *fakeRegistrationMutator) GetRegistration(_ context.Context, req *sapb.RegistrationID, _ ...grpc.CallOption) (*corepb.Registration, error) {
+	return f.reg, nil
+}
+
+func (f *fakeRegistrationMutator) UpdateRegistration(_ context.Context, req *corepb.Registration, _ ...grpc.CallOption) (*corepb.Registration, error) {
+	f.updatedContacts = req.Contact
+	return req, nil
+}
+
+func TestFixerApplyStrip(t *testing.T) {
+	sac := &fakeRegistrationMutator{reg: &corepb.Registration{Id: 1, Contact: []string{"mailto:good@example.com", "mailto:bad@example.com"}}}
+	f := newFixer(fixStrip, sac, nil, nil)
+
+	diff, err := f.apply(context.Background(), 1, "2026-01-01 00:00:00", []string{"mailto:good@example.com", "mailto:bad@example.com"}, testFindings)
+	test.AssertNotError(t, err, "applying a strip fix")
+	test.AssertDeepEquals(t, diff.After, []string{"mailto:good@example.com"})
+	test.AssertDeepEquals(t, sac.updatedContacts, []string{"mailto:good@example.com"})
+}
+
+// TestFixerApplyStripStaleSnapshot covers the case where the registration's
+// contacts have changed since the audit SELECT read them (e.g. the
+// subscriber updated them, or an earlier --fix pass already ran): the strip
+// must be computed and applied against the freshly fetched contacts, not the
+// stale ones the audit scan saw, and the emitted diff must reflect that.
+func TestFixerApplyStripStaleSnapshot(t *testing.T) {
+	// The audit scan saw "bad" and "other-bad" as the contacts; by the time
+	// strip runs, the subscriber has already removed "other-bad" themselves
+	// and added a new "new-good" entry.
+	sac := &fakeRegistrationMutator{reg: &corepb.Registration{Id: 1, Contact: []string{"mailto:bad@example.com", "mailto:new-good@example.com"}}}
+	f := newFixer(fixStrip, sac, nil, nil)
+
+	staleContacts := []string{"mailto:bad@example.com", "mailto:other-bad@example.com"}
+	diff, err := f.apply(context.Background(), 1, "2026-01-01 00:00:00", staleContacts, testFindings)
+	test.AssertNotError(t, err, "applying a strip fix over a stale snapshot")
+	test.AssertDeepEquals(t, diff.Before, []string{"mailto:bad@example.com", "mailto:new-good@example.com"})
+	test.AssertDeepEquals(t, diff.After, []string{"mailto:new-good@example.com"})
+	test.AssertDeepEquals(t, sac.updatedContacts, []string{"mailto:new-good@example.com"})
+}
+
+// TestFixerApplyStripStaleSnapshotAlreadyFixed covers the case where a
+// previous --fix=strip pass already removed every invalid contact: applying
+// again must be a no-op rather than clobbering the registration with a diff
+// computed off the stale, now-invalid-in-a-different-way snapshot.
+func TestFixerApplyStripStaleSnapshotAlreadyFixed(t *testing.T) {
+	sac := &fakeRegistrationMutator{reg: &corepb.Registration{Id: 1, Contact: []string{"mailto:good@example.com"}}}
+	f := newFixer(fixStrip, sac, nil, nil)
+
+	staleContacts := []string{"mailto:good@example.com", "mailto:bad@example.com"}
+	diff, err := f.apply(context.Background(), 1, "2026-01-01 00:00:00", staleContacts, testFindings)
+	test.AssertNotError(t, err, "applying a strip fix that's already been applied")
+	if diff != nil {
+		t.Errorf("expected a nil diff when the registration's current contacts have no invalid entries left, got: %+v", diff)
+	}
+	if sac.updatedContacts != nil {
+		t.Errorf("expected no UpdateRegistration call, got updated contacts: %v", sac.updatedContacts)
+	}
+}
+
+func TestFixerApplyQuarantine(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	test.AssertNotError(t, err, "creating sqlmock")
+	defer db.Close()
+
+	mock.ExpectExec("INSERT INTO contact_audit_quarantine").
+		WithArgs(int64(1), "2026-01-01 00:00:00", `["mailto:bad@example.com"]`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	f := newFixer(fixQuarantine, nil, db, nil)
+
+	diff, err := f.apply(context.Background(), 1, "2026-01-01 00:00:00", []string{"mailto:good@example.com", "mailto:bad@example.com"}, testFindings)
+	test.AssertNotError(t, err, "applying a quarantine fix")
+	test.AssertDeepEquals(t, diff.After, []string{"mailto:good@example.com"})
+
+	err = mock.ExpectationsWereMet()
+	test.AssertNotError(t, err, "quarantine insert expectations")
+}
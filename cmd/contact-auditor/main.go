@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -12,16 +13,26 @@ import (
 	"time"
 
 	"github.com/go-sql-driver/mysql"
+	"github.com/prometheus/client_golang/prometheus"
+
 	"github.com/letsencrypt/boulder/cmd"
 	blog "github.com/letsencrypt/boulder/log"
-	"github.com/letsencrypt/boulder/policy"
 )
 
 type contactAuditor struct {
-	db            *sql.DB
-	resultsFile   *os.File
-	writeToStdout bool
-	logger        blog.Logger
+	db             *sql.DB
+	resultsFile    *os.File
+	writeToStdout  bool
+	emitter        resultEmitter
+	validators     []Validator
+	chunkSize      int64
+	shardCount     int64
+	shardIndex     int64
+	checkpointPath string
+	fixer          *fixer
+	metrics        *auditorMetrics
+	summary        *auditSummary
+	logger         blog.Logger
 }
 
 type result struct {
@@ -39,45 +50,73 @@ func unmarshalContact(contact []byte) ([]string, error) {
 	return contacts, nil
 }
 
-func validateContacts(id int64, createdAt string, contacts []string) error {
-	// Setup a buffer to store any validation problems we encounter.
-	var probsBuff strings.Builder
-
-	// Helper to write validation problems to our buffer.
-	writeProb := func(contact string, prob string) {
-		// Add validation problem to buffer.
-		fmt.Fprintf(&probsBuff, "%d\t%s\tvalidation\t%q\t%q\n", id, createdAt, contact, prob)
+// beginAuditQuery executes the audit query for the next chunkSize rows with
+// id > afterID and returns a cursor used to stream the results. It uses
+// keyset pagination rather than OFFSET so that large tables can be scanned
+// without the query planner materializing an ever-growing temp table, and
+// an EXISTS subquery in place of the prior INNER JOIN DISTINCT for the same
+// reason. When shardCount is greater than 1, the scan is additionally
+// restricted to the ids belonging to this auditor's shardIndex, so that
+// multiple auditor processes can partition the table and run in parallel.
+func (c contactAuditor) beginAuditQuery(afterID int64) (*sql.Rows, error) {
+	query, args := buildAuditQuery(afterID, c.chunkSize, c.shardCount, c.shardIndex)
+
+	rows, err := c.db.Query(query, args...)
+	if err != nil {
+		return nil, err
 	}
+	return rows, nil
+}
 
-	for _, contact := range contacts {
-		if strings.HasPrefix(contact, "mailto:") {
-			err := policy.ValidEmail(strings.TrimPrefix(contact, "mailto:"))
-			if err != nil {
-				writeProb(contact, err.Error())
-			}
-		} else {
-			writeProb(contact, "missing 'mailto:' prefix")
-		}
+// buildAuditQuery renders the keyset-paginated, optionally sharded audit
+// query and its positional arguments. It's factored out of beginAuditQuery
+// so the shard/pagination arithmetic can be unit tested without a database.
+func buildAuditQuery(afterID, chunkSize, shardCount, shardIndex int64) (string, []any) {
+	query := strings.Builder{}
+	query.WriteString(
+		`SELECT r.id, r.contact, r.createdAt
+		FROM registrations AS r
+		WHERE r.id > ?
+			AND r.contact NOT IN ('[]', 'null')
+			AND EXISTS (SELECT 1 FROM certificates AS c WHERE c.registrationID = r.id)`)
+	args := []any{afterID}
+
+	if shardCount > 1 {
+		query.WriteString(" AND r.id % ? = ?")
+		args = append(args, shardCount, shardIndex)
 	}
 
-	if probsBuff.Len() != 0 {
-		return errors.New(probsBuff.String())
+	query.WriteString(" ORDER BY r.id LIMIT ?")
+	args = append(args, chunkSize)
+
+	return query.String(), args
+}
+
+// emitFinding renders f using the auditor's configured emitter and writes
+// the result to the configured sinks. Emitter errors are logged rather than
+// returned, since a single malformed finding shouldn't abort the audit.
+func (c contactAuditor) emitFinding(f finding) {
+	c.metrics.findingsTotal.WithLabelValues(string(f.ProblemCode)).Inc()
+	c.summary.recordFinding(f)
+
+	rendered, err := c.emitter.emit(f)
+	if err != nil {
+		c.logger.Errf("Error while rendering finding for registration ID %d: %s", f.RegistrationID, err)
+		return
 	}
-	return nil
+	c.writeResults(rendered)
 }
 
-// beginAuditQuery executes the audit query and returns a cursor used to
-// stream the results.
-func (c contactAuditor) beginAuditQuery() (*sql.Rows, error) {
-	rows, err := c.db.Query(
-		`SELECT DISTINCT r.id, r.contact, r.createdAt
-		FROM registrations AS r
-			INNER JOIN certificates AS c on c.registrationID = r.id
-		WHERE r.contact NOT IN ('[]', 'null');`)
+// fetchMaxID returns the highest registration id in the table, used to
+// estimate the audit's completion percentage. It returns 0 if the table is
+// empty.
+func (c contactAuditor) fetchMaxID() (int64, error) {
+	var maxID sql.NullInt64
+	err := c.db.QueryRow("SELECT MAX(id) FROM registrations").Scan(&maxID)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	return rows, nil
+	return maxID.Int64, nil
 }
 
 func (c contactAuditor) writeResults(result string) {
@@ -96,47 +135,103 @@ func (c contactAuditor) writeResults(result string) {
 	}
 }
 
-// run retrieves a cursor from `beginAuditQuery` and then audits the
-// `contact` column of all returned rows for abnormalities or policy
-// violations.
+// run pages through the registrations table in chunks of c.chunkSize,
+// starting from the last checkpointed id (or 0, on a fresh run), and audits
+// the `contact` column of all returned rows for abnormalities or policy
+// violations. After each chunk it checkpoints its progress so that an
+// interrupted audit can resume rather than rescan from the beginning.
 func (c contactAuditor) run(resChan chan *result) error {
-	c.logger.Infof("Beginning database query")
-	rows, err := c.beginAuditQuery()
+	afterID, err := c.loadCheckpoint()
+	if err != nil {
+		return err
+	}
+
+	maxID, err := c.fetchMaxID()
 	if err != nil {
+		c.metrics.dbErrors.Inc()
 		return err
 	}
 
-	for rows.Next() {
-		var id int64
-		var contact []byte
-		var createdAt string
-		err := rows.Scan(&id, &contact, &createdAt)
+	for {
+		c.logger.Infof("Beginning database query for registrations with id > %d", afterID)
+		queryStart := time.Now()
+		rows, err := c.beginAuditQuery(afterID)
 		if err != nil {
+			c.metrics.dbErrors.Inc()
 			return err
 		}
 
-		contacts, err := unmarshalContact(contact)
+		var rowsInChunk int64
+		for rows.Next() {
+			var id int64
+			var contact []byte
+			var createdAt string
+			err := rows.Scan(&id, &contact, &createdAt)
+			if err != nil {
+				c.metrics.dbErrors.Inc()
+				rows.Close()
+				return err
+			}
+			rowsInChunk++
+			afterID = id
+
+			contacts, err := unmarshalContact(contact)
+			if err != nil {
+				c.emitFinding(finding{
+					RegistrationID: id,
+					CreatedAt:      createdAt,
+					Contact:        string(contact),
+					ProblemCode:    problemUnmarshalError,
+					ProblemMessage: err.Error(),
+					Severity:       severityError,
+				})
+			}
+
+			findings := c.validateContacts(id, createdAt, contacts)
+			for _, f := range findings {
+				c.emitFinding(f)
+			}
+
+			if c.fixer != nil && len(findings) > 0 {
+				diff, err := c.fixer.apply(context.Background(), id, createdAt, contacts, findings)
+				if err != nil {
+					c.logger.Errf("Error while fixing registration ID %d: %s", id, err)
+				} else if diff != nil {
+					c.writeResults(mustMarshal(diff) + "\n")
+				}
+			}
+
+			// Only used for testing.
+			if resChan != nil {
+				resChan <- &result{id, contacts, createdAt}
+			}
+		}
+		// Ensure the query wasn't interrupted before it could complete.
+		err = rows.Close()
 		if err != nil {
-			c.writeResults(fmt.Sprintf("%d\t%s\tunmarshal\t%q\t%q\n", id, createdAt, contact, err))
+			c.metrics.dbErrors.Inc()
+			return err
+		}
+
+		c.metrics.queryDuration.Observe(time.Since(queryStart).Seconds())
+		c.metrics.rowsScanned.Add(float64(rowsInChunk))
+		c.summary.rowsScanned += rowsInChunk
+		if maxID > 0 {
+			c.metrics.completionPct.Set(100 * float64(afterID) / float64(maxID))
 		}
 
-		err = validateContacts(id, createdAt, contacts)
+		err = c.saveCheckpoint(afterID)
 		if err != nil {
-			c.writeResults(err.Error())
+			return err
 		}
 
-		// Only used for testing.
-		if resChan != nil {
-			resChan <- &result{id, contacts, createdAt}
+		if rowsInChunk < c.chunkSize {
+			// The last chunk was a partial page, so there's nothing left to scan.
+			break
 		}
 	}
-	// Ensure the query wasn't interrupted before it could complete.
-	err = rows.Close()
-	if err != nil {
-		return err
-	} else {
-		c.logger.Info("Query completed successfully")
-	}
+
+	c.logger.Info("Query completed successfully")
 
 	// Only used for testing.
 	if resChan != nil {
@@ -164,17 +259,50 @@ func main() {
 	configFile := flag.String("config", "", "File containing a JSON config.")
 	writeToStdout := flag.Bool("to-stdout", false, "Print the audit results to stdout.")
 	writeToFile := flag.Bool("to-file", false, "Write the audit results to a file.")
+	format := flag.String("format", "tsv", "Output format for results, one of: tsv, ndjson.")
+	chunkSize := flag.Int64("chunk-size", 10000, "Number of registrations to fetch per keyset-paginated query.")
+	shards := flag.Int64("shards", 1, "Total number of auditor processes splitting the id-space between them.")
+	shardIndex := flag.Int64("shard-index", 0, "This process's shard, in [0, shards).")
+	checkpointFile := flag.String("checkpoint-file", "", "Path to a file used to checkpoint progress, so an interrupted audit can resume.")
+	fixFlag := flag.String("fix", "", "Mutate invalid registrations, one of: dry-run, quarantine, strip. Unset disables fixing entirely.")
+	confirmToken := flag.String("confirm-token", "", "HMAC-SHA256 of the --fix mode, keyed by contactAuditor.fix.confirmationSecret, hex encoded. Required when --fix is quarantine or strip.")
+	metricsAddr := flag.String("metrics-addr", "", "Address to serve Prometheus metrics on, e.g. :8006. Unset disables the metrics server.")
 	flag.Parse()
 
 	logger := cmd.NewLogger(cmd.SyslogConfig{StdoutLevel: 7})
 
+	registry := prometheus.NewRegistry()
+	metrics := newAuditorMetrics(registry)
+	if *metricsAddr != "" {
+		serveMetrics(*metricsAddr, registry, logger)
+	}
+
+	if *chunkSize < 1 {
+		cmd.FailOnError(errors.New("--chunk-size must be at least 1"), "Invalid flags")
+	}
+	if *shards < 1 {
+		cmd.FailOnError(errors.New("--shards must be at least 1"), "Invalid flags")
+	}
+	if *shardIndex < 0 || *shardIndex >= *shards {
+		cmd.FailOnError(fmt.Errorf("--shard-index must be in [0, %d)", *shards), "Invalid flags")
+	}
+
+	emitter, err := newResultEmitter(*format)
+	cmd.FailOnError(err, "Couldn't set up result emitter")
+
 	// Load config from JSON.
 	configData, err := ioutil.ReadFile(*configFile)
 	cmd.FailOnError(err, fmt.Sprintf("Error reading config file: %q", *configFile))
 
 	type config struct {
 		ContactAuditor struct {
-			DB cmd.DBConfig
+			DB         cmd.DBConfig
+			Validators validatorsConfig
+			Fix        struct {
+				SAService          *cmd.GRPCClientConfig
+				TLS                cmd.TLSConfig
+				ConfirmationSecret string
+			}
 		}
 	}
 
@@ -182,6 +310,9 @@ func main() {
 	err = json.Unmarshal(configData, &cfg)
 	cmd.FailOnError(err, "Couldn't unmarshal config")
 
+	validators, err := buildValidators(cfg.ContactAuditor.Validators)
+	cmd.FailOnError(err, "Couldn't set up contact validators")
+
 	// Setup database client.
 	dbURL, err := cfg.ContactAuditor.DB.URL()
 	cmd.FailOnError(err, "Couldn't load dbURL")
@@ -194,24 +325,54 @@ func main() {
 	db.SetConnMaxLifetime(cfg.ContactAuditor.DB.ConnMaxLifetime.Duration)
 	db.SetConnMaxIdleTime(cfg.ContactAuditor.DB.ConnMaxIdleTime.Duration)
 
+	var auditorFixer *fixer
+	if *fixFlag != "" {
+		mode, err := parseFixMode(*fixFlag)
+		cmd.FailOnError(err, "Invalid --fix")
+
+		err = checkConfirmationToken(mode, cfg.ContactAuditor.Fix.ConfirmationSecret, *confirmToken)
+		cmd.FailOnError(err, "Refusing to run --fix without a valid confirmation token")
+
+		var sac registrationMutator
+		if mode == fixStrip {
+			if cfg.ContactAuditor.Fix.SAService == nil {
+				cmd.FailOnError(errors.New("--fix=strip requires contactAuditor.fix.saService to be configured"), "Invalid config")
+			}
+			sac, err = setupSAClient(*cfg.ContactAuditor.Fix.SAService, cfg.ContactAuditor.Fix.TLS)
+			cmd.FailOnError(err, "Couldn't set up SA client")
+		}
+
+		auditorFixer = newFixer(mode, sac, db, logger)
+	}
+
 	var resultsFile *os.File
 	if *writeToFile {
 		resultsFile, err = os.Create(
-			fmt.Sprintf("contact-audit-%s.tsv", time.Now().Format("2006-01-02T15:04")),
+			fmt.Sprintf("contact-audit-%s.%s", time.Now().Format("2006-01-02T15:04"), *format),
 		)
 		cmd.FailOnError(err, "Failed to create results file")
 	}
 
 	// Setup and run contact-auditor.
 	auditor := contactAuditor{
-		db:            db,
-		resultsFile:   resultsFile,
-		writeToStdout: *writeToStdout,
-		logger:        logger,
+		db:             db,
+		resultsFile:    resultsFile,
+		writeToStdout:  *writeToStdout,
+		emitter:        emitter,
+		validators:     validators,
+		chunkSize:      *chunkSize,
+		shardCount:     *shards,
+		shardIndex:     *shardIndex,
+		checkpointPath: *checkpointFile,
+		fixer:          auditorFixer,
+		metrics:        metrics,
+		summary:        newAuditSummary(),
+		logger:         logger,
 	}
 
 	logger.Info("Running contact-auditor")
 
+	runStart := time.Now()
 	err = auditor.run(nil)
 	cmd.FailOnError(err, "Audit was interrupted, results may be incomplete")
 
@@ -222,4 +383,9 @@ func main() {
 		resultsFile.Close()
 	}
 
+	report := auditor.summary.report(time.Since(runStart))
+	fmt.Println(report.human())
+	reportJSON, err := report.json()
+	cmd.FailOnError(err, "Couldn't render summary report")
+	fmt.Println(reportJSON)
 }
\ No newline at end of file
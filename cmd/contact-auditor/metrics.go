@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	blog "github.com/letsencrypt/boulder/log"
+)
+
+// auditorMetrics holds the Prometheus instruments exported by a single
+// contact-auditor run.
+type auditorMetrics struct {
+	rowsScanned   prometheus.Counter
+	findingsTotal *prometheus.CounterVec
+	queryDuration prometheus.Histogram
+	dbErrors      prometheus.Counter
+	completionPct prometheus.Gauge
+}
+
+// newAuditorMetrics constructs and registers an auditorMetrics against reg.
+func newAuditorMetrics(reg prometheus.Registerer) *auditorMetrics {
+	m := &auditorMetrics{
+		rowsScanned: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "contact_auditor_rows_scanned_total",
+			Help: "Total number of registration rows scanned.",
+		}),
+		findingsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "contact_auditor_findings_total",
+			Help: "Total number of findings emitted, labeled by problem code.",
+		}, []string{"code"}),
+		queryDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "contact_auditor_query_duration_seconds",
+			Help:    "Time taken to execute and fully scan a single audit query chunk.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		dbErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "contact_auditor_db_errors_total",
+			Help: "Total number of database errors encountered.",
+		}),
+		completionPct: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "contact_auditor_completion_percent",
+			Help: "Estimated completion percentage of the audit, based on the highest registration id scanned so far over MAX(id).",
+		}),
+	}
+	reg.MustRegister(m.rowsScanned, m.findingsTotal, m.queryDuration, m.dbErrors, m.completionPct)
+	return m
+}
+
+// serveMetrics exposes reg's metrics at /metrics on addr for the lifetime of
+// the process. Errors from the listener (e.g. the address is already in
+// use) are logged rather than fatal, since a run shouldn't abort just
+// because it couldn't be scraped.
+func serveMetrics(addr string, reg *prometheus.Registry, logger blog.Logger) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	go func() {
+		err := http.ListenAndServe(addr, mux)
+		if err != nil {
+			logger.Errf("Metrics server on %q exited: %s", addr, err)
+		}
+	}()
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestBuildAuditQueryUnsharded(t *testing.T) {
+	query, args := buildAuditQuery(42, 100, 1, 0)
+
+	if strings.Contains(query, "r.id % ?") {
+		t.Errorf("unsharded query shouldn't contain a shard filter, got: %s", query)
+	}
+	test.AssertDeepEquals(t, args, []any{int64(42), int64(100)})
+}
+
+func TestBuildAuditQuerySharded(t *testing.T) {
+	query, args := buildAuditQuery(42, 100, 4, 2)
+
+	if !strings.Contains(query, "r.id % ? = ?") {
+		t.Errorf("sharded query should contain a shard filter, got: %s", query)
+	}
+	test.AssertDeepEquals(t, args, []any{int64(42), int64(4), int64(2), int64(100)})
+}
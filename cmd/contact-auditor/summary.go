@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// topOffendingDomainsCount bounds how many domains are included in a
+// summaryReport's top-offenders list.
+const topOffendingDomainsCount = 10
+
+// auditSummary accumulates the counters needed to produce an end-of-run
+// summaryReport. It's only ever touched from the single goroutine that
+// calls contactAuditor.run, so it needs no locking.
+type auditSummary struct {
+	rowsScanned    int64
+	findingsByCode map[problemCode]int64
+	domainCounts   map[string]int64
+}
+
+func newAuditSummary() *auditSummary {
+	return &auditSummary{
+		findingsByCode: make(map[problemCode]int64),
+		domainCounts:   make(map[string]int64),
+	}
+}
+
+// recordFinding updates the summary's per-code and per-domain tallies for a
+// single finding.
+func (s *auditSummary) recordFinding(f finding) {
+	s.findingsByCode[f.ProblemCode]++
+
+	domain := domainOf(f.Contact)
+	if domain != "" {
+		s.domainCounts[domain]++
+	}
+}
+
+// domainOf extracts and lowercases the part of contact after its first '@',
+// with any "mailto:" prefix stripped first, returning "" if contact has no
+// '@'. A contact lacking the "mailto:" prefix still yields its domain.
+func domainOf(contact string) string {
+	email := strings.TrimPrefix(contact, "mailto:")
+	_, domain, found := strings.Cut(email, "@")
+	if !found {
+		return ""
+	}
+	return strings.ToLower(domain)
+}
+
+// domainCount is one entry in a summaryReport's top-offenders list.
+type domainCount struct {
+	Domain string `json:"domain"`
+	Count  int64  `json:"count"`
+}
+
+// summaryReport is the end-of-run report printed after an audit completes.
+type summaryReport struct {
+	SchemaVersion        int                   `json:"schema_version"`
+	RegistrationsScanned int64                 `json:"registrations_scanned"`
+	FindingsByCode       map[problemCode]int64 `json:"findings_by_code"`
+	TopOffendingDomains  []domainCount         `json:"top_offending_domains"`
+	WallClockSeconds     float64               `json:"wall_clock_seconds"`
+}
+
+// report renders s as a summaryReport, given the total wall-clock duration
+// of the run.
+func (s *auditSummary) report(wallClock time.Duration) summaryReport {
+	domains := make([]domainCount, 0, len(s.domainCounts))
+	for domain, count := range s.domainCounts {
+		domains = append(domains, domainCount{Domain: domain, Count: count})
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		if domains[i].Count != domains[j].Count {
+			return domains[i].Count > domains[j].Count
+		}
+		return domains[i].Domain < domains[j].Domain
+	})
+	if len(domains) > topOffendingDomainsCount {
+		domains = domains[:topOffendingDomainsCount]
+	}
+
+	return summaryReport{
+		SchemaVersion:        schemaVersion,
+		RegistrationsScanned: s.rowsScanned,
+		FindingsByCode:       s.findingsByCode,
+		TopOffendingDomains:  domains,
+		WallClockSeconds:     wallClock.Seconds(),
+	}
+}
+
+// json renders r as a single line of JSON.
+func (r summaryReport) json() (string, error) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// human renders r as a multi-line, human-readable summary suitable for
+// printing at the end of an interactive run.
+func (r summaryReport) human() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Audit summary:\n")
+	fmt.Fprintf(&b, "  Registrations scanned: %d\n", r.RegistrationsScanned)
+	fmt.Fprintf(&b, "  Wall clock:             %s\n", time.Duration(r.WallClockSeconds*float64(time.Second)))
+
+	fmt.Fprintf(&b, "  Findings by problem code:\n")
+	if len(r.FindingsByCode) == 0 {
+		fmt.Fprintf(&b, "    (none)\n")
+	}
+	codes := make([]problemCode, 0, len(r.FindingsByCode))
+	for code := range r.FindingsByCode {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	for _, code := range codes {
+		fmt.Fprintf(&b, "    %-32s %d\n", code, r.FindingsByCode[code])
+	}
+
+	fmt.Fprintf(&b, "  Top offending domains:\n")
+	if len(r.TopOffendingDomains) == 0 {
+		fmt.Fprintf(&b, "    (none)\n")
+	}
+	for _, dc := range r.TopOffendingDomains {
+		fmt.Fprintf(&b, "    %-32s %d\n", dc.Domain, dc.Count)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestDomainOf(t *testing.T) {
+	testCases := []struct {
+		contact string
+		want    string
+	}{
+		{"mailto:user@Example.COM", "example.com"},
+		{"mailto:user@example.com", "example.com"},
+		{"not-a-mailto-contact", ""},
+		{"mailto:no-at-sign", ""},
+	}
+
+	for _, tc := range testCases {
+		got := domainOf(tc.contact)
+		test.AssertEquals(t, got, tc.want)
+	}
+}
+
+func TestAuditSummaryReportSortsByCountThenDomain(t *testing.T) {
+	s := newAuditSummary()
+	s.rowsScanned = 3
+
+	// "b.com" and "c.com" tie on count and should fall back to alphabetical
+	// order; "a.com" has the highest count and should sort first.
+	s.recordFinding(finding{Contact: "mailto:x@a.com", ProblemCode: problemInvalidEmailSyntax})
+	s.recordFinding(finding{Contact: "mailto:y@a.com", ProblemCode: problemInvalidEmailSyntax})
+	s.recordFinding(finding{Contact: "mailto:z@c.com", ProblemCode: problemInvalidEmailSyntax})
+	s.recordFinding(finding{Contact: "mailto:w@b.com", ProblemCode: problemInvalidEmailSyntax})
+
+	report := s.report(time.Second)
+	test.AssertEquals(t, report.RegistrationsScanned, int64(3))
+	test.AssertEquals(t, len(report.TopOffendingDomains), 3)
+	test.AssertEquals(t, report.TopOffendingDomains[0].Domain, "a.com")
+	test.AssertEquals(t, report.TopOffendingDomains[0].Count, int64(2))
+	test.AssertEquals(t, report.TopOffendingDomains[1].Domain, "b.com")
+	test.AssertEquals(t, report.TopOffendingDomains[2].Domain, "c.com")
+}
+
+func TestAuditSummaryReportTruncatesToTopOffenders(t *testing.T) {
+	s := newAuditSummary()
+
+	for i := 0; i < topOffendingDomainsCount+5; i++ {
+		domain := string(rune('a' + i))
+		s.recordFinding(finding{Contact: "mailto:x@" + domain + ".com", ProblemCode: problemInvalidEmailSyntax})
+	}
+
+	report := s.report(time.Second)
+	test.AssertEquals(t, len(report.TopOffendingDomains), topOffendingDomainsCount)
+}
+
+func TestAuditSummaryReportIgnoresMalformedContacts(t *testing.T) {
+	s := newAuditSummary()
+
+	s.recordFinding(finding{Contact: "not-a-mailto-contact", ProblemCode: problemMissingMailtoPrefix})
+
+	report := s.report(time.Second)
+	test.AssertEquals(t, len(report.TopOffendingDomains), 0)
+	test.AssertEquals(t, report.FindingsByCode[problemMissingMailtoPrefix], int64(1))
+}
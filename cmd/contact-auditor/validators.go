@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/time/rate"
+
+	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/policy"
+)
+
+// validatorsConfig controls which optional Validators are registered, and
+// how they're tuned. The syntax validator is always registered and has no
+// config of its own.
+type validatorsConfig struct {
+	// EnableLengthCheck rejects addresses exceeding RFC 5321 length limits.
+	EnableLengthCheck bool
+	// EnableIDNCheck rejects domains that can't be normalized to punycode.
+	EnableIDNCheck bool
+
+	// EnableMXCheck rejects domains with no MX record.
+	EnableMXCheck bool
+	// MXCacheTTL controls how long a domain's MX lookup result is cached.
+	// Defaults to 1 hour.
+	MXCacheTTL cmd.ConfigDuration
+
+	// EnableSMTPCheck opts in to probing contacts' mail servers with an
+	// SMTP RCPT command. Off by default: it's intrusive and slow.
+	EnableSMTPCheck bool
+	// SMTPRatePerSecond bounds how many RCPT probes are sent per second,
+	// across all domains. Defaults to 1.
+	SMTPRatePerSecond float64
+	// SMTPHelloName is the hostname sent in the SMTP HELO/EHLO command.
+	SMTPHelloName string
+	// SMTPFromAddr is the MAIL FROM address used for the probe.
+	SMTPFromAddr string
+
+	// BlocklistPath, if set, is a path to a newline-delimited file of
+	// disposable/known-bounce domains to reject.
+	BlocklistPath string
+}
+
+// buildValidators constructs the list of Validators enabled by cfg. The
+// syntax validator is always included first.
+func buildValidators(cfg validatorsConfig) ([]Validator, error) {
+	validators := []Validator{syntaxValidator{}}
+
+	if cfg.EnableLengthCheck {
+		validators = append(validators, lengthValidator{})
+	}
+
+	if cfg.EnableIDNCheck {
+		validators = append(validators, idnValidator{})
+	}
+
+	if cfg.EnableMXCheck {
+		cacheTTL := cfg.MXCacheTTL.Duration
+		if cacheTTL == 0 {
+			cacheTTL = time.Hour
+		}
+		validators = append(validators, newMXValidator(cacheTTL))
+	}
+
+	if cfg.EnableSMTPCheck {
+		ratePerSecond := cfg.SMTPRatePerSecond
+		if ratePerSecond == 0 {
+			ratePerSecond = 1
+		}
+		validators = append(validators, newSMTPValidator(ratePerSecond, cfg.SMTPHelloName, cfg.SMTPFromAddr))
+	}
+
+	if cfg.BlocklistPath != "" {
+		bv, err := newBlocklistValidator(cfg.BlocklistPath)
+		if err != nil {
+			return nil, err
+		}
+		validators = append(validators, bv)
+	}
+
+	return validators, nil
+}
+
+// validationProblem is returned by a Validator when it finds a problem with
+// a contact's email address.
+type validationProblem struct {
+	code    problemCode
+	message string
+}
+
+// Validator inspects a single contact's email address and reports any
+// problem it finds. Validators are run in the order they were registered,
+// and a single email can accumulate a finding from more than one Validator.
+type Validator interface {
+	// Name returns a short identifier for the Validator. The Validators
+	// section of the config enables validators by its Enable* fields, not
+	// by name.
+	Name() string
+	// Validate inspects email, which has already had its "mailto:" prefix
+	// stripped, and returns a non-nil problem if it finds one.
+	Validate(email string) *validationProblem
+}
+
+// validateContacts runs every registered Validator against each contact and
+// returns a finding for every problem found. A bare `missing_mailto_prefix`
+// finding is produced for contacts lacking the scheme, since none of the
+// registered Validators know how to make sense of a non-mailto contact.
+func (c contactAuditor) validateContacts(id int64, createdAt string, contacts []string) []finding {
+	var findings []finding
+
+	for _, contact := range contacts {
+		if !strings.HasPrefix(contact, "mailto:") {
+			findings = append(findings, finding{
+				RegistrationID: id,
+				CreatedAt:      createdAt,
+				Contact:        contact,
+				ProblemCode:    problemMissingMailtoPrefix,
+				ProblemMessage: "missing 'mailto:' prefix",
+				Severity:       severityError,
+			})
+			continue
+		}
+
+		email := strings.TrimPrefix(contact, "mailto:")
+		for _, v := range c.validators {
+			prob := v.Validate(email)
+			if prob == nil {
+				continue
+			}
+			findings = append(findings, finding{
+				RegistrationID: id,
+				CreatedAt:      createdAt,
+				Contact:        contact,
+				ProblemCode:    prob.code,
+				ProblemMessage: prob.message,
+				Severity:       severityError,
+			})
+		}
+	}
+
+	return findings
+}
+
+// syntaxValidator wraps the policy package's RFC 5322 syntax check. It's
+// always enabled, since it's the bare minimum sanity check we've always
+// performed.
+type syntaxValidator struct{}
+
+func (syntaxValidator) Name() string { return "syntax" }
+
+func (syntaxValidator) Validate(email string) *validationProblem {
+	err := policy.ValidEmail(email)
+	if err != nil {
+		return &validationProblem{code: problemInvalidEmailSyntax, message: err.Error()}
+	}
+	return nil
+}
+
+// Limits from RFC 5321 4.5.3.1.
+const (
+	rfc5321MaxLocalPartLen = 64
+	rfc5321MaxDomainLen    = 255
+	rfc5321MaxPathLen      = 254
+)
+
+// lengthValidator rejects addresses which exceed the length limits imposed
+// by RFC 5321, which are stricter than the syntax-only checks in
+// policy.ValidEmail.
+type lengthValidator struct{}
+
+func (lengthValidator) Name() string { return "length" }
+
+func (lengthValidator) Validate(email string) *validationProblem {
+	local, domain, found := strings.Cut(email, "@")
+	if !found {
+		// Malformed addresses are the syntax validator's problem to report.
+		return nil
+	}
+	if len(local) > rfc5321MaxLocalPartLen {
+		return &validationProblem{
+			code:    problemAddressTooLong,
+			message: fmt.Sprintf("local part is %d octets, exceeds RFC 5321 limit of %d", len(local), rfc5321MaxLocalPartLen),
+		}
+	}
+	if len(domain) > rfc5321MaxDomainLen {
+		return &validationProblem{
+			code:    problemAddressTooLong,
+			message: fmt.Sprintf("domain is %d octets, exceeds RFC 5321 limit of %d", len(domain), rfc5321MaxDomainLen),
+		}
+	}
+	if len(email) > rfc5321MaxPathLen {
+		return &validationProblem{
+			code:    problemAddressTooLong,
+			message: fmt.Sprintf("address is %d octets, exceeds RFC 5321 limit of %d", len(email), rfc5321MaxPathLen),
+		}
+	}
+	return nil
+}
+
+// idnValidator normalizes the domain part of internationalized addresses to
+// punycode, surfacing a finding when a domain can't be normalized (a strong
+// signal that it isn't a valid DNS name at all).
+type idnValidator struct{}
+
+func (idnValidator) Name() string { return "idn" }
+
+func (idnValidator) Validate(email string) *validationProblem {
+	_, domain, found := strings.Cut(email, "@")
+	if !found {
+		return nil
+	}
+	_, err := idna.ToASCII(domain)
+	if err != nil {
+		return &validationProblem{
+			code:    problemIDNNormalization,
+			message: fmt.Sprintf("failed to normalize domain %q to punycode: %s", domain, err),
+		}
+	}
+	return nil
+}
+
+// mxCacheEntry is a cached result of a single domain's MX lookup.
+type mxCacheEntry struct {
+	problem   *validationProblem
+	expiresAt time.Time
+}
+
+// mxValidator confirms that a contact's domain has at least one MX record,
+// via a per-domain cache so that the same domain (common across many
+// registrations) is only looked up once per cache lifetime. validateContacts
+// is only ever called from run()'s single goroutine, so there's no
+// concurrent lookup traffic to bound here.
+type mxValidator struct {
+	lookupMX func(domain string) ([]*net.MX, error)
+	cacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]mxCacheEntry
+}
+
+// newMXValidator constructs an mxValidator which caches results for
+// cacheTTL.
+func newMXValidator(cacheTTL time.Duration) *mxValidator {
+	return &mxValidator{
+		lookupMX: net.LookupMX,
+		cacheTTL: cacheTTL,
+		cache:    make(map[string]mxCacheEntry),
+	}
+}
+
+func (v *mxValidator) Name() string { return "mx" }
+
+func (v *mxValidator) Validate(email string) *validationProblem {
+	_, domain, found := strings.Cut(email, "@")
+	if !found {
+		return nil
+	}
+	domain = strings.ToLower(domain)
+
+	v.mu.Lock()
+	entry, ok := v.cache[domain]
+	v.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.problem
+	}
+
+	mxs, err := v.lookupMX(domain)
+
+	var problem *validationProblem
+	switch {
+	case err != nil:
+		problem = &validationProblem{code: problemMXLookupFailed, message: err.Error()}
+	case len(mxs) == 0:
+		problem = &validationProblem{code: problemNoMXRecord, message: fmt.Sprintf("domain %q has no MX records", domain)}
+	}
+
+	v.mu.Lock()
+	v.cache[domain] = mxCacheEntry{problem: problem, expiresAt: time.Now().Add(v.cacheTTL)}
+	v.mu.Unlock()
+
+	return problem
+}
+
+// smtpValidator opt-in probes a contact's mail server with an SMTP RCPT TO
+// command to check deliverability, without sending any mail. It's rate
+// limited since probing is expensive and can be mistaken for abuse by the
+// receiving mail server.
+type smtpValidator struct {
+	limiter  *rate.Limiter
+	lookupMX func(domain string) ([]*net.MX, error)
+	dial     func(addr string) (*smtp.Client, error)
+	heloName string
+	fromAddr string
+}
+
+// newSMTPValidator constructs an smtpValidator which probes at most
+// ratePerSecond RCPT commands per second.
+func newSMTPValidator(ratePerSecond float64, heloName, fromAddr string) *smtpValidator {
+	return &smtpValidator{
+		limiter:  rate.NewLimiter(rate.Limit(ratePerSecond), 1),
+		lookupMX: net.LookupMX,
+		dial: func(addr string) (*smtp.Client, error) {
+			return smtp.Dial(addr)
+		},
+		heloName: heloName,
+		fromAddr: fromAddr,
+	}
+}
+
+func (v *smtpValidator) Name() string { return "smtp" }
+
+func (v *smtpValidator) Validate(email string) *validationProblem {
+	_, domain, found := strings.Cut(email, "@")
+	if !found {
+		return nil
+	}
+
+	mxs, err := v.lookupMX(domain)
+	if err != nil || len(mxs) == 0 {
+		// The mx validator, if enabled, is responsible for reporting this.
+		return nil
+	}
+
+	err = v.limiter.Wait(context.Background())
+	if err != nil {
+		return &validationProblem{code: problemSMTPRCPTRejected, message: fmt.Sprintf("rate limiter: %s", err)}
+	}
+
+	client, err := v.dial(net.JoinHostPort(strings.TrimSuffix(mxs[0].Host, "."), "25"))
+	if err != nil {
+		return &validationProblem{code: problemSMTPRCPTRejected, message: fmt.Sprintf("connecting to %s: %s", mxs[0].Host, err)}
+	}
+	defer client.Close()
+
+	err = client.Hello(v.heloName)
+	if err == nil {
+		err = client.Mail(v.fromAddr)
+	}
+	if err == nil {
+		err = client.Rcpt(email)
+	}
+	if err != nil {
+		return &validationProblem{code: problemSMTPRCPTRejected, message: err.Error()}
+	}
+	return nil
+}
+
+// blocklistValidator flags contacts whose domain appears in a configured
+// list of known-disposable or known-bounce domains.
+type blocklistValidator struct {
+	domains map[string]struct{}
+}
+
+// newBlocklistValidator loads a newline-delimited list of domains from
+// path, one domain per line, ignoring blank lines and lines beginning with
+// '#'.
+func newBlocklistValidator(path string) (*blocklistValidator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening blocklist %q: %w", path, err)
+	}
+	defer f.Close()
+
+	domains := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading blocklist %q: %w", path, err)
+	}
+
+	return &blocklistValidator{domains: domains}, nil
+}
+
+func (v *blocklistValidator) Name() string { return "blocklist" }
+
+func (v *blocklistValidator) Validate(email string) *validationProblem {
+	_, domain, found := strings.Cut(email, "@")
+	if !found {
+		return nil
+	}
+	if _, blocked := v.domains[strings.ToLower(domain)]; blocked {
+		return &validationProblem{code: problemDisposableDomain, message: fmt.Sprintf("domain %q is on the disposable/bounce blocklist", domain)}
+	}
+	return nil
+}
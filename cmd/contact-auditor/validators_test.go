@@ -0,0 +1,138 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestLengthValidator(t *testing.T) {
+	v := lengthValidator{}
+
+	testCases := []struct {
+		name  string
+		email string
+		code  problemCode
+	}{
+		{"local part at limit", strings.Repeat("a", rfc5321MaxLocalPartLen) + "@b.co", ""},
+		{"local part over limit", strings.Repeat("a", rfc5321MaxLocalPartLen+1) + "@b.co", problemAddressTooLong},
+		{"domain over limit", "a@" + strings.Repeat("b", rfc5321MaxDomainLen+1) + ".com", problemAddressTooLong},
+		{"malformed address has no '@'", "not-an-email", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prob := v.Validate(tc.email)
+			if tc.code == "" {
+				if prob != nil {
+					t.Errorf("expected no problem for %q, got: %+v", tc.email, prob)
+				}
+				return
+			}
+			if prob == nil {
+				t.Fatalf("expected a problem for %q, got none", tc.email)
+			}
+			test.AssertEquals(t, prob.code, tc.code)
+		})
+	}
+}
+
+func TestLengthValidatorPathLimit(t *testing.T) {
+	v := lengthValidator{}
+
+	// Neither the local part nor domain alone exceeds its own limit, but
+	// the full address exceeds the overall RFC 5321 path limit.
+	local := strings.Repeat("a", 60)
+	domain := strings.Repeat("b", 190) + ".com"
+	email := local + "@" + domain
+	if len(email) <= rfc5321MaxPathLen {
+		t.Fatalf("test fixture doesn't actually exceed the path limit: %d octets", len(email))
+	}
+
+	prob := v.Validate(email)
+	if prob == nil {
+		t.Fatalf("expected a problem for an over-length path, got none")
+	}
+	test.AssertEquals(t, prob.code, problemAddressTooLong)
+}
+
+func TestBlocklistValidator(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "blocklist.txt")
+	contents := "# disposable domains\n\nMailinator.com\ntempmail.com\n  \n# trailing comment\nguerrillamail.com\n"
+	err := os.WriteFile(path, []byte(contents), 0644)
+	test.AssertNotError(t, err, "writing blocklist fixture")
+
+	v, err := newBlocklistValidator(path)
+	test.AssertNotError(t, err, "loading blocklist")
+
+	if prob := v.Validate("user@mailinator.com"); prob == nil {
+		t.Errorf("expected a blocklist hit for a domain differing only in case")
+	} else {
+		test.AssertEquals(t, prob.code, problemDisposableDomain)
+	}
+
+	if prob := v.Validate("user@guerrillamail.com"); prob == nil {
+		t.Errorf("expected a blocklist hit for a domain following a comment line")
+	}
+
+	if prob := v.Validate("user@example.com"); prob != nil {
+		t.Errorf("expected no blocklist hit for a domain not on the list, got: %+v", prob)
+	}
+}
+
+func TestNewBlocklistValidatorMissingFile(t *testing.T) {
+	_, err := newBlocklistValidator(filepath.Join(t.TempDir(), "does-not-exist.txt"))
+	if err == nil {
+		t.Errorf("expected an error loading a nonexistent blocklist, got none")
+	}
+}
+
+func TestMXValidatorCacheHit(t *testing.T) {
+	lookups := 0
+	v := newMXValidator(time.Hour)
+	v.lookupMX = func(domain string) ([]*net.MX, error) {
+		lookups++
+		return []*net.MX{{Host: "mx.example.com."}}, nil
+	}
+
+	if prob := v.Validate("user@example.com"); prob != nil {
+		t.Errorf("expected no problem on first lookup, got: %+v", prob)
+	}
+	if prob := v.Validate("user@example.com"); prob != nil {
+		t.Errorf("expected no problem on cached lookup, got: %+v", prob)
+	}
+	test.AssertEquals(t, lookups, 1)
+}
+
+func TestMXValidatorCacheExpiry(t *testing.T) {
+	lookups := 0
+	v := newMXValidator(time.Millisecond)
+	v.lookupMX = func(domain string) ([]*net.MX, error) {
+		lookups++
+		return []*net.MX{{Host: "mx.example.com."}}, nil
+	}
+
+	v.Validate("user@example.com")
+	time.Sleep(10 * time.Millisecond)
+	v.Validate("user@example.com")
+	test.AssertEquals(t, lookups, 2)
+}
+
+func TestMXValidatorNoRecords(t *testing.T) {
+	v := newMXValidator(time.Hour)
+	v.lookupMX = func(domain string) ([]*net.MX, error) {
+		return nil, nil
+	}
+
+	prob := v.Validate("user@example.com")
+	if prob == nil {
+		t.Fatalf("expected a problem for a domain with no MX records, got none")
+	}
+	test.AssertEquals(t, prob.code, problemNoMXRecord)
+}